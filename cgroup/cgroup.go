@@ -0,0 +1,134 @@
+// Package cgroup reads cgroup v2 (unified hierarchy) boundaries for a
+// process. On containerized hosts these are often the real ceiling on
+// threads/fds: RLIMIT_NPROC is commonly left unlimited while the container's
+// pids controller is what actually kills new forks.
+package cgroup
+
+import (
+	"fmt"
+	"io/ioutil"
+	"math"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// root is a var rather than a const so tests can point effectiveMax/
+// unifiedPath at a scratch directory instead of the real /sys/fs/cgroup.
+var root = "/sys/fs/cgroup"
+
+// ApproxBytesPerFD is a rough estimate of the kernel memory (struct file,
+// dentry, inode) an open file descriptor costs, used to turn a cgroup's
+// memory.max into an implied fd ceiling. It's not a real kernel-enforced
+// limit the way pids.max is, just an early-warning approximation for
+// containers that hit memory pressure from fd-heavy workloads - callers
+// should label any column fed by MemoryFDLimits as an estimate rather than
+// a hard boundary. 1024 bytes/fd is a round-number placeholder, not measured
+// against a specific kernel; it's exported so a deployment that has actually
+// measured its own struct-file/dentry/inode footprint can override it.
+var ApproxBytesPerFD uint64 = 1024
+
+// Limits holds a current/max pair for one cgroup-enforced resource.
+type Limits struct {
+	Current uint64
+	Max     uint64
+}
+
+func readTrimmed(file string) (string, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+func readUint(file string) (uint64, error) {
+	str, err := readTrimmed(file)
+	if err != nil {
+		return 0, err
+	}
+	if str == "max" {
+		return math.MaxUint64, nil
+	}
+	return strconv.ParseUint(str, 10, 64)
+}
+
+// unifiedPath returns pid's cgroup directory under /sys/fs/cgroup, found by
+// reading /proc/<pid>/cgroup. A process on the unified (v2) hierarchy has a
+// single line there with an empty controller list, e.g. "0::/user.slice/...".
+func unifiedPath(pid string) (string, error) {
+	str, err := readTrimmed(filepath.Join("/proc", pid, "cgroup"))
+	if err != nil {
+		return "", err
+	}
+	rel, ok := parseUnifiedPath(str)
+	if !ok {
+		return "", fmt.Errorf("cgroup: pid %s has no cgroup v2 (unified) hierarchy", pid)
+	}
+	return filepath.Join(root, rel), nil
+}
+
+// parseUnifiedPath is the pure parsing core of unifiedPath, split out so it
+// can be tested against sample /proc/<pid>/cgroup text directly. It returns
+// the v2 (unified) line's path and true, or false if no such line exists.
+func parseUnifiedPath(str string) (string, bool) {
+	for _, line := range strings.Split(str, "\n") {
+		fields := strings.SplitN(line, ":", 3)
+		if len(fields) == 3 && fields[1] == "" {
+			return fields[2], true
+		}
+	}
+	return "", false
+}
+
+// effectiveMax walks from dir up to the cgroup root, returning the tightest
+// value of file found along the way. A directory with no such file, or one
+// set to "max", doesn't constrain the result.
+func effectiveMax(dir, file string) uint64 {
+	best := uint64(math.MaxUint64)
+	for {
+		if v, err := readUint(filepath.Join(dir, file)); err == nil && v < best {
+			best = v
+		}
+		if dir == root || dir == "/" || dir == "." {
+			break
+		}
+		dir = filepath.Dir(dir)
+	}
+	return best
+}
+
+// PidsLimits returns the effective pids.current/pids.max for pid: its own
+// cgroup's task count, and the tightest pids.max set by that cgroup or any
+// of its parents up to the root.
+func PidsLimits(pid string) (Limits, error) {
+	dir, err := unifiedPath(pid)
+	if err != nil {
+		return Limits{}, err
+	}
+
+	current, err := readUint(filepath.Join(dir, "pids.current"))
+	if err != nil {
+		return Limits{}, err
+	}
+
+	return Limits{Current: current, Max: effectiveMax(dir, "pids.max")}, nil
+}
+
+// MemoryFDLimits approximates the fd ceiling implied by pid's cgroup memory
+// limit: its current open fd count against memory.max/ApproxBytesPerFD. It's
+// a heuristic "how many fds before we likely OOM", not a real boundary the
+// kernel enforces, so callers should treat it as advisory.
+func MemoryFDLimits(pid string, openFds uint64) (Limits, error) {
+	dir, err := unifiedPath(pid)
+	if err != nil {
+		return Limits{}, err
+	}
+
+	memMax := effectiveMax(dir, "memory.max")
+	if memMax == math.MaxUint64 {
+		return Limits{}, fmt.Errorf("cgroup: pid %s has no memory.max set", pid)
+	}
+
+	return Limits{Current: openFds, Max: memMax / ApproxBytesPerFD}, nil
+}
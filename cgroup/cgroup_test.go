@@ -0,0 +1,96 @@
+package cgroup
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseUnifiedPath(t *testing.T) {
+	cases := []struct {
+		name string
+		in   string
+		want string
+		ok   bool
+	}{
+		{
+			name: "unified hierarchy",
+			in:   "0::/user.slice/user-1000.slice/session-1.scope",
+			want: "/user.slice/user-1000.slice/session-1.scope",
+			ok:   true,
+		},
+		{
+			name: "hybrid hierarchy, v2 line among v1 controller lines",
+			in: "12:pids:/user.slice\n" +
+				"1:name=systemd:/user.slice/session-1.scope\n" +
+				"0::/user.slice/session-1.scope",
+			want: "/user.slice/session-1.scope",
+			ok:   true,
+		},
+		{
+			name: "no unified line",
+			in:   "12:pids:/user.slice\n1:name=systemd:/user.slice/session-1.scope",
+			want: "",
+			ok:   false,
+		},
+		{
+			name: "empty",
+			in:   "",
+			want: "",
+			ok:   false,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got, ok := parseUnifiedPath(c.in)
+			if got != c.want || ok != c.ok {
+				t.Errorf("parseUnifiedPath(%q) = (%q, %v), want (%q, %v)", c.in, got, ok, c.want, c.ok)
+			}
+		})
+	}
+}
+
+// withRoot points root at dir for the duration of the test.
+func withRoot(t *testing.T, dir string) {
+	t.Helper()
+	old := root
+	root = dir
+	t.Cleanup(func() { root = old })
+}
+
+func TestEffectiveMax(t *testing.T) {
+	base := t.TempDir()
+	withRoot(t, base)
+
+	parent := filepath.Join(base, "user.slice")
+	child := filepath.Join(parent, "user-1000.slice")
+	if err := os.MkdirAll(child, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("tightest ancestor wins", func(t *testing.T) {
+		if err := os.WriteFile(filepath.Join(parent, "pids.max"), []byte("100\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(child, "pids.max"), []byte("500\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if got := effectiveMax(child, "pids.max"); got != 100 {
+			t.Errorf("effectiveMax = %d, want 100 (the tighter ancestor limit)", got)
+		}
+	})
+
+	t.Run("max sentinel and missing file don't constrain", func(t *testing.T) {
+		leaf := filepath.Join(base, "unconstrained")
+		if err := os.MkdirAll(leaf, 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(leaf, "memory.max"), []byte("max\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if got := effectiveMax(leaf, "memory.max"); got != math.MaxUint64 {
+			t.Errorf("effectiveMax = %d, want math.MaxUint64", got)
+		}
+	})
+}
@@ -3,6 +3,7 @@ package main
 import (
 	"flag"
 	"fmt"
+	"log"
 	"math"
 	"os"
 	"sort"
@@ -10,6 +11,8 @@ import (
 	"text/tabwriter"
 
 	"github.com/mihgen/softstat"
+	"github.com/mihgen/softstat/cgroup"
+	"github.com/mihgen/softstat/exporter"
 )
 
 type Metric struct {
@@ -32,6 +35,24 @@ type Boundary struct {
 	p   float64
 }
 
+// rlimitMetric describes an additional rlimit that -metric can track: where
+// to find its limit in softstat.ProcLimits, and where to find the matching
+// current value for the process.
+type rlimitMetric struct {
+	limit   func(softstat.ProcLimits) uint64
+	current func(softstat.Task) (uint64, error)
+}
+
+var rlimitMetrics = map[string]rlimitMetric{
+	"data-size":     {func(l softstat.ProcLimits) uint64 { return l.DataSize }, func(t softstat.Task) (uint64, error) { return t.VmField("VmData") }},
+	"stack-size":    {func(l softstat.ProcLimits) uint64 { return l.StackSize }, func(t softstat.Task) (uint64, error) { return t.VmField("VmStk") }},
+	"resident-set":  {func(l softstat.ProcLimits) uint64 { return l.ResidentSet }, func(t softstat.Task) (uint64, error) { return t.VmField("VmRSS") }},
+	"locked-memory": {func(l softstat.ProcLimits) uint64 { return l.LockedMemory }, func(t softstat.Task) (uint64, error) { return t.VmField("VmLck") }},
+	"address-space": {func(l softstat.ProcLimits) uint64 { return l.AddressSpace }, func(t softstat.Task) (uint64, error) { return t.AddressSpace() }},
+	"open-files":    {func(l softstat.ProcLimits) uint64 { return l.OpenFiles }, func(t softstat.Task) (uint64, error) { e, err := t.FdsRlim(); return e.V, err }},
+	"process-count": {func(l softstat.ProcLimits) uint64 { return l.ProcessCount }, func(t softstat.Task) (uint64, error) { e, err := t.NprocRlim(); return e.V, err }},
+}
+
 func CalcBound(m []Metric) (b Boundary) {
 	b.p = -1.0
 	for i := 0; i < len(m); i++ {
@@ -53,18 +74,36 @@ func CalcBound(m []Metric) (b Boundary) {
 
 func main() {
 	var nLines int
+	var metricName, serveAddr string
 	if len(os.Args) == 2 && os.Args[1] == "-1" {
 		nLines = -1
 	} else {
 		flag.IntVar(&nLines, "n", 10, "Output N most loaded processes. Use -1 to list all.")
+		flag.StringVar(&metricName, "metric", "", "Also track this rlimit for %USE/BOUND: data-size, stack-size, resident-set, locked-memory, address-space, open-files, process-count.")
+		flag.StringVar(&serveAddr, "serve", "", "Serve Prometheus metrics on this address (e.g. :9232) instead of printing the table once.")
 		flag.Parse()
 	}
 
+	var metric rlimitMetric
+	if metricName != "" {
+		var ok bool
+		metric, ok = rlimitMetrics[metricName]
+		if !ok {
+			fmt.Fprintf(os.Stderr, "unknown -metric %q\n", metricName)
+			os.Exit(1)
+		}
+	}
+
+	if serveAddr != "" {
+		log.Fatal(exporter.ListenAndServe(serveAddr))
+	}
+
 	// ************** POPULATE CODE ********************
-	tasks := softstat.TasksInit()
-	procTotalLimit := softstat.ProcTotalLimit()
-	fileTotal, fileMax := softstat.FileNr()
-	filePerProcMax := softstat.FilePerProcMax()
+	snap := softstat.Snapshot()
+	tasks := snap.Tasks
+	procTotalLimit := snap.ProcTotalLimit
+	fileTotal, fileMax := snap.FileTotal, snap.FileMax
+	filePerProcMax := snap.FilePerProcMax
 	var out []OutputEntry
 	for _, pid := range tasks.Pids {
 		m := []Metric{{name: "fds-rlim", f: pid.FdsRlim}, {name: "nproc-rlim", f: pid.NprocRlim}}
@@ -73,10 +112,48 @@ func main() {
 			e, _ := m[i].f.(func() (softstat.Entry, error))()
 			m[i].res = e
 		}
+		if metricName != "" {
+			// pid.Limits()/metric.current can fail per-pid (process exited,
+			// etc); append the column regardless so every row has the same
+			// number of columns, the same way fds-rlim/nproc-rlim do above.
+			// A failed read is reported as n/a (MaxUint64, like an unlimited
+			// rlimit) rather than 0, so CalcBound doesn't mistake "we don't
+			// know" for "100% used".
+			res := softstat.Entry{Max: math.MaxUint64}
+			if pl, err := pid.Limits(); err == nil {
+				cur, _ := metric.current(pid)
+				res = softstat.Entry{V: cur, Max: metric.limit(pl)}
+			}
+			m = append(m, Metric{name: metricName, res: res})
+		}
+
+		// A container's cgroup v2 controllers are often the real ceiling on
+		// threads/fds, tighter than the rlimit: add them so CalcBound picks
+		// whichever is actually closest. Appended unconditionally, like
+		// fds-rlim/nproc-rlim above, so a pid outside any v2 cgroup still
+		// gets the column instead of leaving the row one short; a failed
+		// read reports as n/a (MaxUint64) rather than a false 100%-used
+		// ceiling.
+		pidsRes := softstat.Entry{Max: math.MaxUint64}
+		if l, err := cgroup.PidsLimits(pid.Pid); err == nil {
+			pidsRes = softstat.Entry{V: l.Current, Max: l.Max}
+		}
+		m = append(m, Metric{name: "cgroup-pids", res: pidsRes})
+
+		// cgroup-memory-fd (approx) is a heuristic, not a kernel-enforced
+		// ceiling - see cgroup.MemoryFDLimits - so it's named and treated
+		// the same as any other column here, but the name makes clear to
+		// the operator that BOUND/%USE pointing at it is an estimate.
+		memFDRes := softstat.Entry{Max: math.MaxUint64}
+		if l, err := cgroup.MemoryFDLimits(pid.Pid, m[0].res.V); err == nil {
+			memFDRes = softstat.Entry{V: l.Current, Max: l.Max}
+		}
+		m = append(m, Metric{name: "cgroup-memory-fd (approx)", res: memFDRes})
+
 		cmd, _ := softstat.CmdName(pid.Pid)
 
 		adds := []Metric{{name: "threads-max", res: softstat.Entry{tasks.Total, procTotalLimit}},
-			{name: "pid_max", res: softstat.Entry{tasks.Total, softstat.PidTotalLimit()}},
+			{name: "pid_max", res: softstat.Entry{tasks.Total, snap.PidMax}},
 			{name: "file-max", res: softstat.Entry{fileTotal, fileMax}},
 			{name: "file-perproc-max", res: softstat.Entry{m[0].res.V, filePerProcMax}}}
 		out = append(out, OutputEntry{pid.Pid, m, CalcBound(append(m, adds...)), cmd})
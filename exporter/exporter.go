@@ -0,0 +1,78 @@
+// Package exporter exposes softstat's per-process and system-wide limits as
+// Prometheus gauges, so softstat can run as a long-lived exporter instead of
+// a one-shot CLI, for alerting on approaching fd/thread limits.
+package exporter
+
+import (
+	"net/http"
+
+	"github.com/mihgen/softstat"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+var (
+	fds          = prometheus.NewDesc("softstat_process_fds", "Open file descriptors held by a process.", []string{"pid", "comm"}, nil)
+	fdsLimit     = prometheus.NewDesc("softstat_process_fds_limit", "RLIMIT_NOFILE for a process.", []string{"pid", "comm"}, nil)
+	threads      = prometheus.NewDesc("softstat_process_threads", "Threads owned by a process's user.", []string{"pid", "comm"}, nil)
+	threadsLimit = prometheus.NewDesc("softstat_process_threads_limit", "RLIMIT_NPROC for a process.", []string{"pid", "comm"}, nil)
+
+	fileNrUsed = prometheus.NewDesc("softstat_file_nr_used", "System-wide open file descriptor count.", nil, nil)
+	fileNrMax  = prometheus.NewDesc("softstat_file_nr_max", "System-wide open file descriptor limit.", nil, nil)
+	threadsMax = prometheus.NewDesc("softstat_threads_max", "System-wide thread limit (kernel.threads-max).", nil, nil)
+	pidMax     = prometheus.NewDesc("softstat_pid_max", "System-wide pid limit (kernel.pid_max).", nil, nil)
+)
+
+// Collector implements prometheus.Collector by taking a fresh
+// softstat.Snapshot() on every scrape.
+type Collector struct{}
+
+// NewCollector returns a Collector ready to be registered with a Prometheus
+// registry.
+func NewCollector() *Collector {
+	return &Collector{}
+}
+
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- fds
+	ch <- fdsLimit
+	ch <- threads
+	ch <- threadsLimit
+	ch <- fileNrUsed
+	ch <- fileNrMax
+	ch <- threadsMax
+	ch <- pidMax
+}
+
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	snap := softstat.Snapshot()
+
+	for _, t := range snap.Tasks.Pids {
+		comm, err := softstat.CmdName(t.Pid)
+		if err != nil {
+			continue
+		}
+
+		if e, err := t.FdsRlim(); err == nil {
+			ch <- prometheus.MustNewConstMetric(fds, prometheus.GaugeValue, float64(e.V), t.Pid, comm)
+			ch <- prometheus.MustNewConstMetric(fdsLimit, prometheus.GaugeValue, float64(e.Max), t.Pid, comm)
+		}
+		if e, err := t.NprocRlim(); err == nil {
+			ch <- prometheus.MustNewConstMetric(threads, prometheus.GaugeValue, float64(e.V), t.Pid, comm)
+			ch <- prometheus.MustNewConstMetric(threadsLimit, prometheus.GaugeValue, float64(e.Max), t.Pid, comm)
+		}
+	}
+
+	ch <- prometheus.MustNewConstMetric(fileNrUsed, prometheus.GaugeValue, float64(snap.FileTotal))
+	ch <- prometheus.MustNewConstMetric(fileNrMax, prometheus.GaugeValue, float64(snap.FileMax))
+	ch <- prometheus.MustNewConstMetric(threadsMax, prometheus.GaugeValue, float64(snap.ProcTotalLimit))
+	ch <- prometheus.MustNewConstMetric(pidMax, prometheus.GaugeValue, float64(snap.PidMax))
+}
+
+// ListenAndServe registers a Collector with the default Prometheus registry
+// and serves it on addr at /metrics, blocking until the server exits.
+func ListenAndServe(addr string) error {
+	prometheus.MustRegister(NewCollector())
+	http.Handle("/metrics", promhttp.Handler())
+	return http.ListenAndServe(addr, nil)
+}
@@ -0,0 +1,103 @@
+// +build linux
+
+package softstat
+
+import (
+	"math"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// reProcLimit matches a single row of /proc/<pid>/limits, e.g.:
+//   Max open files            1024                 4096                 files
+var reProcLimit = regexp.MustCompile(`(?m:^Max ([A-Za-z ]+?)[ \t]{2,}(unlimited|[0-9]+)[ \t]+(unlimited|[0-9]+)[ \t]+)`)
+
+// procLimitFields maps the label /proc/<pid>/limits prints for a resource to
+// the ProcLimits field it belongs in.
+var procLimitFields = map[string]func(*ProcLimits, uint64){
+	"cpu time":          func(l *ProcLimits, v uint64) { l.CPUTime = v },
+	"file size":         func(l *ProcLimits, v uint64) { l.FileSize = v },
+	"data size":         func(l *ProcLimits, v uint64) { l.DataSize = v },
+	"stack size":        func(l *ProcLimits, v uint64) { l.StackSize = v },
+	"core file size":    func(l *ProcLimits, v uint64) { l.CoreFileSize = v },
+	"resident set":      func(l *ProcLimits, v uint64) { l.ResidentSet = v },
+	"processes":         func(l *ProcLimits, v uint64) { l.ProcessCount = v },
+	"open files":        func(l *ProcLimits, v uint64) { l.OpenFiles = v },
+	"locked memory":     func(l *ProcLimits, v uint64) { l.LockedMemory = v },
+	"address space":     func(l *ProcLimits, v uint64) { l.AddressSpace = v },
+	"file locks":        func(l *ProcLimits, v uint64) { l.FileLocks = v },
+	"pending signals":   func(l *ProcLimits, v uint64) { l.PendingSignals = v },
+	"msgqueue size":     func(l *ProcLimits, v uint64) { l.MsgqueueSize = v },
+	"nice priority":     func(l *ProcLimits, v uint64) { l.NicePriority = v },
+	"realtime priority": func(l *ProcLimits, v uint64) { l.RealtimePriority = v },
+	"realtime timeout":  func(l *ProcLimits, v uint64) { l.RealtimeTimeout = v },
+}
+
+// Limits parses /proc/<pid>/limits for every rlimit the kernel reports, in a
+// single pass over the file, instead of issuing one prlimit(2) syscall per
+// resource.
+func (t Task) Limits() (ProcLimits, error) {
+	str, err := ReadAndTrim(filepath.Join("/proc", t.Pid, "limits"))
+	if err != nil {
+		return ProcLimits{}, err
+	}
+	return parseProcLimits(str), nil
+}
+
+// parseProcLimits is the pure parsing core of Limits, split out so it can be
+// tested against sample /proc/<pid>/limits text without touching /proc.
+func parseProcLimits(str string) ProcLimits {
+	var l ProcLimits
+	for _, m := range reProcLimit.FindAllStringSubmatch(str, -1) {
+		set, ok := procLimitFields[strings.ToLower(m[1])]
+		if !ok {
+			continue
+		}
+		set(&l, parseLimitValue(m[2]))
+	}
+	return l
+}
+
+// limitsFromProc is the fallback GetLimits uses when it can't prlimit(2) a
+// pid directly (e.g. it belongs to another user without us holding
+// CAP_SYS_RESOURCE/CAP_SYS_PTRACE). /proc/<pid>/limits always reports the
+// caller's own view of the target's limits, no extra capabilities required.
+//
+// This parses the file itself rather than going through Task.Limits(),
+// because ProcLimits only keeps the soft limit per resource (matching
+// prometheus/procfs), and Limits needs both Cur and Max.
+func limitsFromProc(pid string) (Limits, error) {
+	str, err := ReadAndTrim(filepath.Join("/proc", pid, "limits"))
+	if err != nil {
+		return Limits{}, err
+	}
+	return parseLimitsFromProc(str), nil
+}
+
+// parseLimitsFromProc is the pure parsing core of limitsFromProc, split out
+// so it can be tested against sample /proc/<pid>/limits text without
+// touching /proc.
+func parseLimitsFromProc(str string) Limits {
+	var l Limits
+	for _, m := range reProcLimit.FindAllStringSubmatch(str, -1) {
+		rlim := syscall.Rlimit{Cur: parseLimitValue(m[2]), Max: parseLimitValue(m[3])}
+		switch strings.ToLower(m[1]) {
+		case "open files":
+			l.openFiles = rlim
+		case "processes":
+			l.nProc = rlim
+		}
+	}
+	return l
+}
+
+func parseLimitValue(s string) uint64 {
+	if s == "unlimited" {
+		return math.MaxUint64
+	}
+	v, _ := strconv.ParseUint(s, 10, 64)
+	return v
+}
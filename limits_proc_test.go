@@ -0,0 +1,89 @@
+// +build linux
+
+package softstat
+
+import (
+	"math"
+	"testing"
+)
+
+const sampleProcLimits = `Limit                     Soft Limit           Hard Limit           Units
+Max cpu time              unlimited            unlimited            seconds
+Max file size             unlimited            unlimited            bytes
+Max data size             unlimited            unlimited            bytes
+Max stack size            8388608              unlimited            bytes
+Max core file size        0                    unlimited            bytes
+Max resident set          unlimited            unlimited            bytes
+Max processes             31365                31365                processes
+Max open files            1024                 4096                 files
+Max locked memory         67108864             67108864             bytes
+Max address space         unlimited            unlimited            bytes
+Max file locks            unlimited            unlimited            locks
+Max pending signals       31365                31365                signals
+Max msgqueue size         819200               819200               bytes
+Max nice priority         0                    0
+Max realtime priority     0                    0
+Max realtime timeout      unlimited            unlimited            us
+`
+
+func TestParseProcLimits(t *testing.T) {
+	l := parseProcLimits(sampleProcLimits)
+
+	if l.StackSize != 8388608 {
+		t.Errorf("StackSize = %d, want 8388608", l.StackSize)
+	}
+	if l.CoreFileSize != 0 {
+		t.Errorf("CoreFileSize = %d, want 0", l.CoreFileSize)
+	}
+	if l.ProcessCount != 31365 {
+		t.Errorf("ProcessCount = %d, want 31365", l.ProcessCount)
+	}
+	// OpenFiles is the soft limit (column 2): 1024, not the hard limit 4096.
+	if l.OpenFiles != 1024 {
+		t.Errorf("OpenFiles = %d, want 1024 (soft limit, not hard)", l.OpenFiles)
+	}
+	if l.AddressSpace != math.MaxUint64 {
+		t.Errorf("AddressSpace = %d, want math.MaxUint64 for unlimited", l.AddressSpace)
+	}
+}
+
+func TestParseProcLimits_empty(t *testing.T) {
+	l := parseProcLimits("")
+	if l != (ProcLimits{}) {
+		t.Errorf("parseProcLimits(\"\") = %+v, want zero value", l)
+	}
+}
+
+func TestParseLimitsFromProc(t *testing.T) {
+	l := parseLimitsFromProc(sampleProcLimits)
+
+	// Regression check for the hard-limit-discarding bug fixed in 94448ad:
+	// both soft (Cur) and hard (Max) must be preserved, not just the soft
+	// value duplicated into both fields.
+	if l.openFiles.Cur != 1024 {
+		t.Errorf("openFiles.Cur = %d, want 1024", l.openFiles.Cur)
+	}
+	if l.openFiles.Max != 4096 {
+		t.Errorf("openFiles.Max = %d, want 4096", l.openFiles.Max)
+	}
+	if l.nProc.Cur != 31365 || l.nProc.Max != 31365 {
+		t.Errorf("nProc = %+v, want Cur=Max=31365", l.nProc)
+	}
+}
+
+func TestParseLimitValue(t *testing.T) {
+	cases := []struct {
+		in   string
+		want uint64
+	}{
+		{"unlimited", math.MaxUint64},
+		{"0", 0},
+		{"1024", 1024},
+		{"31365", 31365},
+	}
+	for _, c := range cases {
+		if got := parseLimitValue(c.in); got != c.want {
+			t.Errorf("parseLimitValue(%q) = %d, want %d", c.in, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,30 @@
+// +build linux
+
+package softstat
+
+import (
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// Prlimit reads (and optionally sets) the rlimit for resource in the target
+// process pid. It delegates to golang.org/x/sys/unix.Prlimit, which carries
+// the correct prlimit(2) syscall number and rlimit layout for every arch Go
+// supports, rather than the single hardcoded linux/amd64 syscall number this
+// used to shell out to directly.
+func Prlimit(pid int, resource int, newRlim *syscall.Rlimit, oldRlim *syscall.Rlimit) (err error) {
+	var old unix.Rlimit
+	var newPtr *unix.Rlimit
+	if newRlim != nil {
+		newVal := unix.Rlimit{Cur: newRlim.Cur, Max: newRlim.Max}
+		newPtr = &newVal
+	}
+
+	err = unix.Prlimit(pid, resource, newPtr, &old)
+	if oldRlim != nil {
+		oldRlim.Cur = old.Cur
+		oldRlim.Max = old.Max
+	}
+	return err
+}
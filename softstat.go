@@ -16,7 +16,6 @@ package softstat
 
 import (
 	"io/ioutil"
-	"math"
 	"os"
 	"path/filepath"
 	"regexp"
@@ -46,25 +45,86 @@ type Entry struct {
 	Max uint64
 }
 
-func GetLimits(pid string) (Limits, error) {
-	var mylimit Limits
-	var rlim syscall.Rlimit
-	pidNu, _ := strconv.Atoi(pid)
-
-	err := Prlimit(pidNu, syscall.RLIMIT_NOFILE, nil, &rlim)
-	mylimit.openFiles = rlim
+// ProcLimits mirrors prometheus/procfs's ProcLimits: the soft limit of every
+// resource the kernel reports via /proc/<pid>/limits, in the kernel's own
+// units (bytes, file count, ...). An unbounded resource reads as
+// math.MaxUint64. Only Task.Limits() on Linux populates this; other
+// platforms have no equivalent per-resource breakdown.
+type ProcLimits struct {
+	CPUTime          uint64
+	FileSize         uint64
+	DataSize         uint64
+	StackSize        uint64
+	CoreFileSize     uint64
+	ResidentSet      uint64
+	ProcessCount     uint64
+	OpenFiles        uint64
+	LockedMemory     uint64
+	AddressSpace     uint64
+	FileLocks        uint64
+	PendingSignals   uint64
+	MsgqueueSize     uint64
+	NicePriority     uint64
+	RealtimePriority uint64
+	RealtimeTimeout  uint64
+}
+
+// SysLimits holds the system-wide boundaries a Source's SystemLimits reports:
+// how many file descriptors and processes/threads the whole system may have
+// open, and how many file descriptors a single process may have open.
+type SysLimits struct {
+	FileTotal      uint64
+	FileMax        uint64
+	FilePerProcMax uint64
+	ThreadsMax     uint64
+	PidMax         uint64
+}
+
+// Source abstracts the OS-specific mechanics of listing tasks and reading
+// system-wide limits, so the rest of the package doesn't need to know
+// whether it's running against /proc, sysctl(3), or something else.
+type Source interface {
+	Tasks() (*Tasks, error)
+	SystemLimits() (SysLimits, error)
+}
+
+// source is the Source this build was compiled with; newSource is provided
+// by a source_<goos>.go file selected at build time.
+var source Source = newSource()
+
+// TasksInit lists every task on the system along with its limits, using the
+// platform Source.
+func TasksInit() *Tasks {
+	t, err := source.Tasks()
 	if err != nil {
-		return mylimit, err
+		panic(err)
 	}
+	return t
+}
 
-	// syscall.RLIMIT_NPROC is not defined, using number instead
-	// See https://github.com/golang/go/issues/14854 for details
-	err = Prlimit(pidNu, 6, nil, &rlim)
-	mylimit.nProc = rlim
+func systemLimits() SysLimits {
+	l, err := source.SystemLimits()
 	if err != nil {
-		return mylimit, err
+		panic(err)
 	}
-	return mylimit, nil
+	return l
+}
+
+func ProcTotalLimit() uint64 {
+	return systemLimits().ThreadsMax
+}
+
+func PidTotalLimit() uint64 {
+	return systemLimits().PidMax
+}
+
+func FileNr() (used, max uint64) {
+	l := systemLimits()
+	return l.FileTotal, l.FileMax
+}
+
+func FilePerProcMax() uint64 {
+	return systemLimits().FilePerProcMax
 }
 
 func countFiles(dir string) (uint64, error) {
@@ -80,96 +140,65 @@ func countFiles(dir string) (uint64, error) {
 	return uint64(len(files)), nil
 }
 
-func (t Task) FdsRlim() (Entry, error) {
-	v, err := countFiles(filepath.Join("/proc", t.Pid, "fd"))
-	if err != nil {
-		return Entry{}, err // this process may no longer exist. So let's skip it.
-	}
-	return Entry{v, t.limits.openFiles.Cur}, nil
-}
+var reVmField = regexp.MustCompile(`(?m:^(Vm[A-Za-z]+):[ \t]+([0-9]+) kB)`)
 
-func (t Task) NprocRlim() (Entry, error) {
-	uid, _, err := getStatus(t.Pid)
+// VmField returns the value of a "Vm*" field from /proc/<pid>/status (e.g.
+// "VmData", "VmStk", "VmRSS", "VmLck"), converted from kB to bytes so it's
+// directly comparable to the corresponding ProcLimits field.
+func (t Task) VmField(name string) (uint64, error) {
+	str, err := ReadAndTrim(filepath.Join("/proc", t.Pid, "status"))
 	if err != nil {
-		return Entry{}, err
+		return 0, err
 	}
-	return Entry{t.UidMap[uid], t.limits.nProc.Cur}, nil
-}
-
-func getStatus(pid string) (uid string, threads uint64, err error) {
-	str, err := ReadAndTrim(filepath.Join("/proc", pid, "status"))
-	if err != nil {
-		// we can't do anything for this pid. It may not exist anymore, or we don't have enough capabilities
-		return
+	for _, m := range reVmField.FindAllStringSubmatch(str, -1) {
+		if m[1] == name {
+			v, err := strconv.ParseUint(m[2], 10, 64)
+			return v * 1024, err
+		}
 	}
-
-	reUid := regexp.MustCompile(`(?m:^Uid:[ \t]+([0-9]+)[ \t]+)`)
-	matchedUid := reUid.FindStringSubmatch(str)
-	// TODO: what if we can't parse? Need to do error-handling
-	uid = matchedUid[1]
-
-	reThreads := regexp.MustCompile(`(?m:^Threads:[ \t]+([0-9]+))`)
-	matchedThreads := reThreads.FindStringSubmatch(str)
-	// TODO: what if we can't parse? Need to do error-handling
-	threads, err = strconv.ParseUint(matchedThreads[1], 10, 64)
-	return
+	return 0, nil
 }
 
-func ProcTotalLimit() uint64 {
-	str, err := ReadAndTrim("/proc/sys/kernel/threads-max")
+// AddressSpace returns the process's current virtual memory size in bytes,
+// read from the vsize field (in pages) of /proc/<pid>/statm.
+func (t Task) AddressSpace() (uint64, error) {
+	str, err := ReadAndTrim(filepath.Join("/proc", t.Pid, "statm"))
 	if err != nil {
-		panic(err)
+		return 0, err
 	}
-	threadsMax, err := strconv.ParseUint(str, 10, 64)
+	vsize, err := strconv.ParseUint(strings.Fields(str)[0], 10, 64)
 	if err != nil {
-		panic(err)
+		return 0, err
 	}
-	return threadsMax
+	return vsize * uint64(os.Getpagesize()), nil
 }
 
-func PidTotalLimit() uint64 {
-	str, err := ReadAndTrim("/proc/sys/kernel/pid_max")
-	if err != nil {
-		panic(err)
-	}
-	v, err := strconv.ParseUint(str, 10, 64)
-	if err != nil {
-		panic(err)
-	}
-	return v
+// SystemSnapshot is a single point-in-time view of every process's limits
+// plus the system-wide boundaries they're measured against. The CLI table
+// and the Prometheus exporter each take their own snapshot through the same
+// Snapshot() call, so both report the same fields gathered the same way.
+type SystemSnapshot struct {
+	Tasks          *Tasks
+	ProcTotalLimit uint64
+	PidMax         uint64
+	FileTotal      uint64
+	FileMax        uint64
+	FilePerProcMax uint64
 }
 
-func TasksInit() *Tasks {
-	t := new(Tasks)
-	byUid := make(map[string]uint64)
-
-	procs, err := filepath.Glob("/proc/[0-9]*")
-	if err != nil {
-		panic(err)
-	}
-	for _, p := range procs {
-		pid := strings.Split(p, "/")[2]
-
-		uid, threads, err := getStatus(pid)
-		if err != nil {
-			// process may no longer exist, so we just skip pid with errors
-			// TODO: need to have better error handling here.
-			// One of issues could be that we simply can't open any file, as we reached FD limit ourselves.
-			continue
-		}
-		// TODO: error handling
-		l, _ := GetLimits(pid)
-		// User with uid=0 has no RLIMIT enforcement on number of tasks. threads-max is still applied though.
-		if uid == "0" {
-			// TODO: we need to check for CAP_SYS_RESOURCE & CAP_SYS_ADMIN too
-			// http://lxr.free-electrons.com/source/kernel/fork.c#L1529
-			l.nProc.Cur = math.MaxUint64
-		}
-		byUid[uid] += threads
-		t.Pids = append(t.Pids, Task{Pid: pid, limits: l, UidMap: byUid})
-		t.Total += threads
+// Snapshot collects the current state of every process and the system-wide
+// limits in one pass.
+func Snapshot() *SystemSnapshot {
+	tasks := TasksInit()
+	l := systemLimits()
+	return &SystemSnapshot{
+		Tasks:          tasks,
+		ProcTotalLimit: l.ThreadsMax,
+		PidMax:         l.PidMax,
+		FileTotal:      l.FileTotal,
+		FileMax:        l.FileMax,
+		FilePerProcMax: l.FilePerProcMax,
 	}
-	return t
 }
 
 func ReadAndTrim(file string) (string, error) {
@@ -179,37 +208,3 @@ func ReadAndTrim(file string) (string, error) {
 	}
 	return strings.TrimSuffix(string(data), "\n"), nil
 }
-
-func CmdName(pid string) (string, error) {
-	return ReadAndTrim(filepath.Join("/proc", pid, "comm"))
-}
-
-func FileNr() (used, max uint64) {
-	str, err := ReadAndTrim("/proc/sys/fs/file-nr")
-	if err != nil {
-		panic(err)
-	}
-	parsed := strings.Split(str, "\t")
-
-	used, err = strconv.ParseUint(parsed[0], 10, 64)
-	if err != nil {
-		panic(err)
-	}
-	max, err = strconv.ParseUint(parsed[2], 10, 64)
-	if err != nil {
-		panic(err)
-	}
-	return
-}
-
-func FilePerProcMax() uint64 {
-	str, err := ReadAndTrim("/proc/sys/fs/nr_open")
-	if err != nil {
-		panic(err)
-	}
-	x, err := strconv.ParseUint(str, 10, 64)
-	if err != nil {
-		panic(err)
-	}
-	return x
-}
@@ -0,0 +1,137 @@
+// +build darwin
+
+package softstat
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"strconv"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// darwinSource is the Darwin Source. It lists every process via the
+// kern.proc.all sysctl, the same mechanism ps(1)/activity monitor use, but
+// unlike Linux's /proc it exposes no portable, unprivileged way to read
+// another process's open fd count or rlimits (that needs libproc/task_info
+// and elevated entitlements). So every task shares the system-wide limits
+// read once in Tasks(), and only the current process's fd count is real (its
+// thread count reads as 1); every other task's usage reads as 0 against that
+// shared limit.
+type darwinSource struct{}
+
+func newSource() Source {
+	return darwinSource{}
+}
+
+func (darwinSource) Tasks() (*Tasks, error) {
+	kps, err := unix.SysctlKinfoProcSlice("kern.proc.all")
+	if err != nil {
+		return nil, err
+	}
+
+	maxFilesPerProc, err := unix.SysctlUint32("kern.maxfilesperproc")
+	if err != nil {
+		return nil, err
+	}
+	maxProc, err := unix.SysctlUint32("kern.maxproc")
+	if err != nil {
+		return nil, err
+	}
+
+	// Darwin has no per-process RLIMIT_NPROC; kern.maxproc is the closest
+	// system-wide analogue, so it's used here too. Every task is handed the
+	// same shared limits: without libproc there's no per-pid rlimit sysctl.
+	limits := Limits{
+		openFiles: syscall.Rlimit{Cur: uint64(maxFilesPerProc), Max: uint64(maxFilesPerProc)},
+		nProc:     syscall.Rlimit{Cur: uint64(maxProc), Max: uint64(maxProc)},
+	}
+
+	t := new(Tasks)
+	for _, kp := range kps {
+		if kp.Proc.P_pid <= 0 {
+			continue
+		}
+		pid := strconv.Itoa(int(kp.Proc.P_pid))
+		t.Pids = append(t.Pids, Task{Pid: pid, limits: limits, UidMap: map[string]uint64{}})
+		t.Total++
+	}
+	return t, nil
+}
+
+func (darwinSource) SystemLimits() (SysLimits, error) {
+	maxFiles, err := unix.SysctlUint32("kern.maxfiles")
+	if err != nil {
+		return SysLimits{}, err
+	}
+	maxFilesPerProc, err := unix.SysctlUint32("kern.maxfilesperproc")
+	if err != nil {
+		return SysLimits{}, err
+	}
+	maxProc, err := unix.SysctlUint32("kern.maxproc")
+	if err != nil {
+		return SysLimits{}, err
+	}
+
+	return SysLimits{
+		// Darwin has no cheap system-wide "files currently open" counter
+		// the way /proc/sys/fs/file-nr does, so FileTotal is left at zero
+		// rather than guessed at.
+		FileMax:        uint64(maxFiles),
+		FilePerProcMax: uint64(maxFilesPerProc),
+		// Darwin has no separate thread cap or pid_max tunable; kern.maxproc
+		// is the closest system-wide analogue to both.
+		ThreadsMax: uint64(maxProc),
+		PidMax:     uint64(maxProc),
+	}, nil
+}
+
+// isSelf reports whether pid is the calling process, the only one whose fd
+// count darwinSource can actually read.
+func isSelf(pid string) bool {
+	return pid == strconv.Itoa(os.Getpid())
+}
+
+func (t Task) FdsRlim() (Entry, error) {
+	if !isSelf(t.Pid) {
+		return Entry{0, t.limits.openFiles.Cur}, nil
+	}
+	// /dev/fd only reflects the calling process's own descriptor table.
+	v, err := countFiles("/dev/fd")
+	if err != nil {
+		return Entry{}, err
+	}
+	return Entry{v, t.limits.openFiles.Cur}, nil
+}
+
+func (t Task) NprocRlim() (Entry, error) {
+	if !isSelf(t.Pid) {
+		return Entry{0, t.limits.nProc.Cur}, nil
+	}
+	return Entry{1, t.limits.nProc.Cur}, nil
+}
+
+// Limits has no Darwin equivalent: there's no per-resource breakdown like
+// /proc/<pid>/limits, only the handful of sysctls already read into Limits
+// above.
+func (t Task) Limits() (ProcLimits, error) {
+	return ProcLimits{}, errors.New("softstat: per-resource limits are not available on darwin")
+}
+
+func CmdName(pid string) (string, error) {
+	pidNum, err := strconv.Atoi(pid)
+	if err != nil {
+		return "", err
+	}
+	kp, err := unix.SysctlKinfoProc("kern.proc.pid", pidNum)
+	if err != nil {
+		return "", err
+	}
+	comm := kp.Proc.P_comm[:]
+	if i := bytes.IndexByte(comm, 0); i >= 0 {
+		comm = comm[:i]
+	}
+	return string(comm), nil
+}
@@ -0,0 +1,108 @@
+// +build freebsd
+
+package softstat
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// freebsdSource is the FreeBSD Source. Darwin lists every process by walking
+// kern.proc.all and parsing the kinfo_proc records golang.org/x/sys/unix
+// decodes for it (KinfoProc, SysctlKinfoProcSlice); that decoding is
+// Darwin-only in this dependency, FreeBSD's kinfo_proc layout differs and
+// has no equivalent helper here. Rather than hand-roll parsing of an
+// unexported kernel struct, freebsdSource stays scoped to the current
+// process, same as the fd/thread limits below.
+type freebsdSource struct{}
+
+func newSource() Source {
+	fmt.Fprintln(os.Stderr, "softstat: WARNING: FreeBSD support only lists the current softstat process, not every process on the system (see freebsdSource in source_freebsd.go) - threads-max/file-max/etc are still system-wide, but the per-process table is not")
+	return freebsdSource{}
+}
+
+func (freebsdSource) Tasks() (*Tasks, error) {
+	pid := strconv.Itoa(os.Getpid())
+
+	var rlim syscall.Rlimit
+	if err := syscall.Getrlimit(syscall.RLIMIT_NOFILE, &rlim); err != nil {
+		return nil, err
+	}
+
+	maxProc, err := unix.SysctlUint32("kern.maxproc")
+	if err != nil {
+		return nil, err
+	}
+
+	limits := Limits{
+		openFiles: rlim,
+		// FreeBSD has no per-process RLIMIT_NPROC; kern.maxproc is the
+		// closest system-wide analogue, so it's used here too.
+		nProc: syscall.Rlimit{Cur: int64(maxProc), Max: int64(maxProc)},
+	}
+
+	return &Tasks{
+		Pids:  []Task{{Pid: pid, limits: limits, UidMap: map[string]uint64{}}},
+		Total: 1,
+	}, nil
+}
+
+func (freebsdSource) SystemLimits() (SysLimits, error) {
+	maxFiles, err := unix.SysctlUint32("kern.maxfiles")
+	if err != nil {
+		return SysLimits{}, err
+	}
+	maxFilesPerProc, err := unix.SysctlUint32("kern.maxfilesperproc")
+	if err != nil {
+		return SysLimits{}, err
+	}
+	maxProc, err := unix.SysctlUint32("kern.maxproc")
+	if err != nil {
+		return SysLimits{}, err
+	}
+	openFiles, err := unix.SysctlUint32("kern.openfiles")
+	if err != nil {
+		return SysLimits{}, err
+	}
+
+	return SysLimits{
+		FileTotal:      uint64(openFiles),
+		FileMax:        uint64(maxFiles),
+		FilePerProcMax: uint64(maxFilesPerProc),
+		// FreeBSD has no separate thread cap or pid_max tunable; kern.maxproc
+		// is the closest system-wide analogue to both.
+		ThreadsMax: uint64(maxProc),
+		PidMax:     uint64(maxProc),
+	}, nil
+}
+
+func (t Task) FdsRlim() (Entry, error) {
+	// /dev/fd only reflects the calling process's own descriptor table,
+	// which lines up with freebsdSource only ever tracking the current pid.
+	v, err := countFiles("/dev/fd")
+	if err != nil {
+		return Entry{}, err
+	}
+	return Entry{v, uint64(t.limits.openFiles.Cur)}, nil
+}
+
+func (t Task) NprocRlim() (Entry, error) {
+	return Entry{1, uint64(t.limits.nProc.Cur)}, nil
+}
+
+// Limits has no FreeBSD equivalent: there's no per-resource breakdown like
+// /proc/<pid>/limits, only the handful of sysctls already read into Limits
+// above.
+func (t Task) Limits() (ProcLimits, error) {
+	return ProcLimits{}, errors.New("softstat: per-resource limits are not available on freebsd")
+}
+
+func CmdName(pid string) (string, error) {
+	return filepath.Base(os.Args[0]), nil
+}
@@ -0,0 +1,155 @@
+// +build linux
+
+package softstat
+
+import (
+	"math"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// linuxSource is the Linux Source: everything comes from /proc.
+type linuxSource struct{}
+
+func newSource() Source {
+	return linuxSource{}
+}
+
+func (linuxSource) Tasks() (*Tasks, error) {
+	t := new(Tasks)
+	byUid := make(map[string]uint64)
+
+	procs, err := filepath.Glob("/proc/[0-9]*")
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range procs {
+		pid := strings.Split(p, "/")[2]
+
+		uid, threads, err := getStatus(pid)
+		if err != nil {
+			// process may no longer exist, so we just skip pid with errors
+			// TODO: need to have better error handling here.
+			// One of issues could be that we simply can't open any file, as we reached FD limit ourselves.
+			continue
+		}
+		// TODO: error handling
+		l, _ := GetLimits(pid)
+		// User with uid=0 has no RLIMIT enforcement on number of tasks. threads-max is still applied though.
+		if uid == "0" {
+			// TODO: we need to check for CAP_SYS_RESOURCE & CAP_SYS_ADMIN too
+			// http://lxr.free-electrons.com/source/kernel/fork.c#L1529
+			l.nProc.Cur = math.MaxUint64
+		}
+		byUid[uid] += threads
+		t.Pids = append(t.Pids, Task{Pid: pid, limits: l, UidMap: byUid})
+		t.Total += threads
+	}
+	return t, nil
+}
+
+func (linuxSource) SystemLimits() (SysLimits, error) {
+	var l SysLimits
+
+	str, err := ReadAndTrim("/proc/sys/kernel/threads-max")
+	if err != nil {
+		return l, err
+	}
+	if l.ThreadsMax, err = strconv.ParseUint(str, 10, 64); err != nil {
+		return l, err
+	}
+
+	str, err = ReadAndTrim("/proc/sys/kernel/pid_max")
+	if err != nil {
+		return l, err
+	}
+	if l.PidMax, err = strconv.ParseUint(str, 10, 64); err != nil {
+		return l, err
+	}
+
+	str, err = ReadAndTrim("/proc/sys/fs/file-nr")
+	if err != nil {
+		return l, err
+	}
+	parsed := strings.Split(str, "\t")
+	if l.FileTotal, err = strconv.ParseUint(parsed[0], 10, 64); err != nil {
+		return l, err
+	}
+	if l.FileMax, err = strconv.ParseUint(parsed[2], 10, 64); err != nil {
+		return l, err
+	}
+
+	str, err = ReadAndTrim("/proc/sys/fs/nr_open")
+	if err != nil {
+		return l, err
+	}
+	if l.FilePerProcMax, err = strconv.ParseUint(str, 10, 64); err != nil {
+		return l, err
+	}
+
+	return l, nil
+}
+
+func GetLimits(pid string) (Limits, error) {
+	var mylimit Limits
+	var rlim syscall.Rlimit
+	pidNu, _ := strconv.Atoi(pid)
+
+	errFds := Prlimit(pidNu, syscall.RLIMIT_NOFILE, nil, &rlim)
+	mylimit.openFiles = rlim
+
+	// syscall.RLIMIT_NPROC is not defined, using number instead
+	// See https://github.com/golang/go/issues/14854 for details
+	errProc := Prlimit(pidNu, 6, nil, &rlim)
+	mylimit.nProc = rlim
+
+	if errFds != nil || errProc != nil {
+		// We likely lack CAP_SYS_RESOURCE/CAP_SYS_PTRACE to prlimit(2) this
+		// pid (e.g. it belongs to another user), so fall back to parsing
+		// /proc/<pid>/limits instead of giving up on it outright.
+		return limitsFromProc(pid)
+	}
+	return mylimit, nil
+}
+
+func (t Task) FdsRlim() (Entry, error) {
+	v, err := countFiles(filepath.Join("/proc", t.Pid, "fd"))
+	if err != nil {
+		return Entry{}, err // this process may no longer exist. So let's skip it.
+	}
+	return Entry{v, t.limits.openFiles.Cur}, nil
+}
+
+func (t Task) NprocRlim() (Entry, error) {
+	uid, _, err := getStatus(t.Pid)
+	if err != nil {
+		return Entry{}, err
+	}
+	return Entry{t.UidMap[uid], t.limits.nProc.Cur}, nil
+}
+
+func getStatus(pid string) (uid string, threads uint64, err error) {
+	str, err := ReadAndTrim(filepath.Join("/proc", pid, "status"))
+	if err != nil {
+		// we can't do anything for this pid. It may not exist anymore, or we don't have enough capabilities
+		return
+	}
+
+	reUid := regexp.MustCompile(`(?m:^Uid:[ \t]+([0-9]+)[ \t]+)`)
+	matchedUid := reUid.FindStringSubmatch(str)
+	// TODO: what if we can't parse? Need to do error-handling
+	uid = matchedUid[1]
+
+	reThreads := regexp.MustCompile(`(?m:^Threads:[ \t]+([0-9]+))`)
+	matchedThreads := reThreads.FindStringSubmatch(str)
+	// TODO: what if we can't parse? Need to do error-handling
+	threads, err = strconv.ParseUint(matchedThreads[1], 10, 64)
+	return
+}
+
+func CmdName(pid string) (string, error) {
+	return ReadAndTrim(filepath.Join("/proc", pid, "comm"))
+}